@@ -0,0 +1,28 @@
+// Package config loads NATS transport/auth settings from the environment,
+// for entry points that wire up a connector.Connector from process config
+// rather than hard-coded options.
+package config
+
+import "os"
+
+// NatsAuth holds the NATS TLS/token/creds settings read from the
+// environment.
+type NatsAuth struct {
+	CAFile    string
+	CertFile  string
+	KeyFile   string
+	Token     string
+	CredsFile string
+}
+
+// NatsAuthFromEnv reads NATS_CA_FILE, NATS_CERT_FILE, NATS_KEY_FILE,
+// NATS_TOKEN, and NATS_CREDS_FILE.
+func NatsAuthFromEnv() NatsAuth {
+	return NatsAuth{
+		CAFile:    os.Getenv("NATS_CA_FILE"),
+		CertFile:  os.Getenv("NATS_CERT_FILE"),
+		KeyFile:   os.Getenv("NATS_KEY_FILE"),
+		Token:     os.Getenv("NATS_TOKEN"),
+		CredsFile: os.Getenv("NATS_CREDS_FILE"),
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNatsAuthFromEnv(t *testing.T) {
+	t.Run("should read every nats auth variable", func(t *testing.T) {
+		t.Setenv("NATS_CA_FILE", "ca.pem")
+		t.Setenv("NATS_CERT_FILE", "cert.pem")
+		t.Setenv("NATS_KEY_FILE", "key.pem")
+		t.Setenv("NATS_TOKEN", "s3cr3t")
+		t.Setenv("NATS_CREDS_FILE", "nats.creds")
+
+		require.Equal(t, NatsAuth{
+			CAFile:    "ca.pem",
+			CertFile:  "cert.pem",
+			KeyFile:   "key.pem",
+			Token:     "s3cr3t",
+			CredsFile: "nats.creds",
+		}, NatsAuthFromEnv())
+	})
+	t.Run("should zero-value unset variables", func(t *testing.T) {
+		require.Equal(t, NatsAuth{}, NatsAuthFromEnv())
+	})
+}
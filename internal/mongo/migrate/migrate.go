@@ -0,0 +1,69 @@
+// Package migrate is a minimal versioned up-migration runner for the
+// resume-tokens databases: each migration runs at most once, tracked by a
+// schema_migrations record, so repeat startups are no-ops.
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Database is the persistence surface a Runner and its migrations need: a
+// way to check and record which versions have run, and to create the
+// indexes migrations are typically shipped to add.
+type Database interface {
+	// HasApplied reports whether the migration at version has already run.
+	HasApplied(ctx context.Context, version string) (bool, error)
+	// RecordApplied marks version (with its description) as applied.
+	RecordApplied(ctx context.Context, version, description string) error
+	// CreateIndex creates a named index with the given keys on collName.
+	// It's a no-op if an index with that name already exists.
+	CreateIndex(ctx context.Context, collName string, keys bson.D, indexName string) error
+}
+
+// Migration is a single versioned, idempotent schema change.
+type Migration struct {
+	// Version is a semver string, e.g. "0.1.0".
+	Version     string
+	Description string
+	Up          func(ctx context.Context, db Database) error
+}
+
+// Runner applies a fixed list of migrations, in order, skipping any
+// already recorded as applied.
+type Runner struct {
+	migrations []Migration
+}
+
+// NewRunner builds a Runner for the given migrations, applied in the order
+// given.
+func NewRunner(migrations ...Migration) *Runner {
+	return &Runner{migrations: migrations}
+}
+
+// Run applies any migration not yet recorded as applied against db. A
+// failed migration aborts the run without recording it as applied, so the
+// next Run retries it.
+func (r *Runner) Run(ctx context.Context, db Database) error {
+	for _, m := range r.migrations {
+		applied, err := db.HasApplied(ctx, m.Version)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", m.Version, err)
+		}
+		if applied {
+			continue
+		}
+
+		if err := m.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %s (%s) failed: %w", m.Version, m.Description, err)
+		}
+
+		if err := db.RecordApplied(ctx, m.Version, m.Description); err != nil {
+			return fmt.Errorf("failed to record migration %s as applied: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
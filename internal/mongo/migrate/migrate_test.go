@@ -0,0 +1,134 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRunner_Run(t *testing.T) {
+	t.Run("should apply a migration and record it as applied", func(t *testing.T) {
+		db := newFakeDatabase()
+		upCalls := 0
+
+		runner := NewRunner(Migration{
+			Version:     "0.1.0",
+			Description: "test migration",
+			Up: func(ctx context.Context, db Database) error {
+				upCalls++
+				return nil
+			},
+		})
+
+		require.NoError(t, runner.Run(context.Background(), db))
+		require.Equal(t, 1, upCalls)
+		require.True(t, db.applied["0.1.0"])
+	})
+	t.Run("should be idempotent: running twice is a no-op the second time", func(t *testing.T) {
+		db := newFakeDatabase()
+		upCalls := 0
+
+		runner := NewRunner(Migration{
+			Version:     "0.1.0",
+			Description: "test migration",
+			Up: func(ctx context.Context, db Database) error {
+				upCalls++
+				return nil
+			},
+		})
+
+		require.NoError(t, runner.Run(context.Background(), db))
+		require.NoError(t, runner.Run(context.Background(), db))
+		require.Equal(t, 1, upCalls)
+	})
+	t.Run("should abort and not record the migration as applied when Up fails", func(t *testing.T) {
+		db := newFakeDatabase()
+		upErr := errors.New("index creation failed")
+
+		runner := NewRunner(Migration{
+			Version:     "0.1.0",
+			Description: "test migration",
+			Up: func(ctx context.Context, db Database) error {
+				return upErr
+			},
+		})
+
+		err := runner.Run(context.Background(), db)
+		require.ErrorIs(t, err, upErr)
+		require.False(t, db.applied["0.1.0"])
+	})
+	t.Run("should run multiple migrations in order, skipping those already applied", func(t *testing.T) {
+		db := newFakeDatabase()
+		db.applied["0.1.0"] = true
+
+		var ranVersions []string
+
+		runner := NewRunner(
+			Migration{Version: "0.1.0", Up: func(ctx context.Context, db Database) error {
+				ranVersions = append(ranVersions, "0.1.0")
+				return nil
+			}},
+			Migration{Version: "0.2.0", Up: func(ctx context.Context, db Database) error {
+				ranVersions = append(ranVersions, "0.2.0")
+				return nil
+			}},
+		)
+
+		require.NoError(t, runner.Run(context.Background(), db))
+		require.Equal(t, []string{"0.2.0"}, ranVersions)
+	})
+}
+
+func TestResumeTokenClusterTimeIndex(t *testing.T) {
+	t.Run("should create the index on every given collection", func(t *testing.T) {
+		db := newFakeDatabase()
+
+		runner := NewRunner(ResumeTokenClusterTimeIndex([]string{"coll1", "coll2"}))
+		require.NoError(t, runner.Run(context.Background(), db))
+
+		require.ElementsMatch(t, []string{"coll1", "coll2"}, db.indexedCollections("resume_tokens_clusterTime"))
+	})
+}
+
+// fakeDatabase is an in-memory Database used to unit test Runner and the
+// shipped migrations without a real MongoDB deployment.
+type fakeDatabase struct {
+	applied map[string]bool
+	indexes map[string][]string // collName -> index names created on it
+}
+
+func newFakeDatabase() *fakeDatabase {
+	return &fakeDatabase{
+		applied: map[string]bool{},
+		indexes: map[string][]string{},
+	}
+}
+
+func (f *fakeDatabase) HasApplied(_ context.Context, version string) (bool, error) {
+	return f.applied[version], nil
+}
+
+func (f *fakeDatabase) RecordApplied(_ context.Context, version, _ string) error {
+	f.applied[version] = true
+	return nil
+}
+
+func (f *fakeDatabase) CreateIndex(_ context.Context, collName string, _ bson.D, indexName string) error {
+	f.indexes[collName] = append(f.indexes[collName], indexName)
+	return nil
+}
+
+func (f *fakeDatabase) indexedCollections(indexName string) []string {
+	var collNames []string
+	for collName, names := range f.indexes {
+		for _, n := range names {
+			if n == indexName {
+				collNames = append(collNames, collName)
+			}
+		}
+	}
+	return collNames
+}
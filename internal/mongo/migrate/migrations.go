@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ResumeTokenClusterTimeIndex creates a compound {clusterTime: -1, _id: 1}
+// index, named "resume_tokens_clusterTime", on each of collNames. It makes
+// the latest-resume-token lookup (sorted by clusterTime descending, with no
+// filter) index-backed instead of falling back to a collection scan;
+// clusterTime leads since it's the sort key, with _id trailing for
+// documents that tie on clusterTime.
+func ResumeTokenClusterTimeIndex(collNames []string) Migration {
+	return Migration{
+		Version:     "0.1.0",
+		Description: "create the resume_tokens_clusterTime index on resume token collections",
+		Up: func(ctx context.Context, db Database) error {
+			keys := bson.D{
+				{Key: "clusterTime", Value: -1},
+				{Key: "_id", Value: 1},
+			}
+
+			for _, collName := range collNames {
+				if err := db.CreateIndex(ctx, collName, keys, "resume_tokens_clusterTime"); err != nil {
+					return fmt.Errorf("failed to create resume_tokens_clusterTime index on %s: %w", collName, err)
+				}
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/context-labs/mongodb-nats-connector/internal/mongo/migrate"
+)
+
+// migrationsCollName is where applied migration versions are recorded, in
+// the same database as the resume token collections they apply to.
+const migrationsCollName = "schema_migrations"
+
+// migrateDatabase adapts a driver *mongo.Database to migrate.Database.
+type migrateDatabase struct {
+	db *mongo.Database
+}
+
+func newMigrateDatabase(db *mongo.Database) migrate.Database {
+	return &migrateDatabase{db: db}
+}
+
+func (m *migrateDatabase) HasApplied(ctx context.Context, version string) (bool, error) {
+	err := m.db.Collection(migrationsCollName).FindOne(ctx, bson.M{"version": version}).Err()
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (m *migrateDatabase) RecordApplied(ctx context.Context, version, description string) error {
+	coll := m.db.Collection(migrationsCollName)
+
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+
+	_, err := coll.InsertOne(ctx, bson.M{"version": version, "description": description})
+	return err
+}
+
+func (m *migrateDatabase) CreateIndex(ctx context.Context, collName string, keys bson.D, indexName string) error {
+	_, err := m.db.Collection(collName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName(indexName),
+	})
+	return err
+}
@@ -0,0 +1,324 @@
+// Package mongo wraps the official MongoDB driver with the narrow surface
+// the connector needs: creating watched/resume-token collections and
+// streaming change events off of them.
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/context-labs/mongodb-nats-connector/internal/mongo/migrate"
+)
+
+// Pipeline is a MongoDB aggregation pipeline, as accepted by the driver's
+// change stream Watch call.
+type Pipeline = mongo.Pipeline
+
+// ChangeEventHandler is invoked for every change event read off a watched
+// collection's change stream. subj is the stream name qualified with the
+// event's operation type, and msgId is the watched document's _id, so that
+// downstream publishers can deduplicate deliveries.
+type ChangeEventHandler func(ctx context.Context, subj, msgId string, data []byte) error
+
+// Client is the interface the connector depends on, satisfied by the real
+// driver-backed client in production and by a mock in tests.
+type Client interface {
+	Close() error
+	Name() string
+	Monitor(ctx context.Context) error
+	CreateCollection(ctx context.Context, opts *CreateCollectionOptions) error
+	Migrate(ctx context.Context, opts *MigrateOptions) error
+	WatchCollection(ctx context.Context, opts *WatchCollectionOptions) error
+}
+
+// MigrateOptions describes the resume-token collections a Migrate call
+// should bring up to date with the shipped schema migrations.
+type MigrateOptions struct {
+	DbName    string
+	CollNames []string
+}
+
+// CreateCollectionOptions describes a collection to create if it doesn't
+// already exist.
+type CreateCollectionOptions struct {
+	DbName                       string
+	CollName                     string
+	Capped                       bool
+	SizeInBytes                  int64
+	ChangeStreamPreAndPostImages bool
+}
+
+// ResumeMode selects how WatchCollection resumes a change stream that was
+// previously interrupted.
+type ResumeMode int
+
+const (
+	// ResumeModeAfter resumes from the persisted token using resumeAfter,
+	// the default. The server rejects the token if its event has been
+	// invalidated, e.g. by falling out of the oplog window.
+	ResumeModeAfter ResumeMode = iota
+	// ResumeModeStartAfter resumes from the persisted token using
+	// startAfter, which (unlike resumeAfter) tolerates a token whose event
+	// was an invalidate event such as a collection drop or rename.
+	ResumeModeStartAfter
+	// ResumeModeStartAtOperationTime starts the stream at StartAtOperationTime,
+	// ignoring any persisted token.
+	ResumeModeStartAtOperationTime
+)
+
+// WatchCollectionOptions describes a watched collection, where its resume
+// tokens are persisted, and how change events should be delivered.
+type WatchCollectionOptions struct {
+	WatchedDbName          string
+	WatchedCollName        string
+	ResumeTokensDbName     string
+	ResumeTokensCollName   string
+	ResumeTokensCollCapped bool
+	StreamName             string
+	Pipeline               Pipeline
+	ChangeEventHandler     ChangeEventHandler
+
+	ResumeMode           ResumeMode
+	StartAtOperationTime primitive.Timestamp
+	// MaxTokenAge bounds how long a persisted resume token may be trusted.
+	// A token older than MaxTokenAge is abandoned in favor of starting at
+	// now minus MaxTokenAge, logged as a structured event, rather than risk
+	// a resumeAfter/startAfter call failing against a server that's already
+	// rolled the token's position out of its oplog. Zero means no bound.
+	MaxTokenAge time.Duration
+}
+
+type client struct {
+	conn   *mongo.Client
+	name   string
+	logger *slog.Logger
+}
+
+// New connects to the MongoDB deployment at uri.
+func New(ctx context.Context, uri string, logger *slog.Logger) (Client, error) {
+	conn, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongo: %w", err)
+	}
+
+	return &client{conn: conn, name: "mongo", logger: logger}, nil
+}
+
+func (c *client) Close() error {
+	return c.conn.Disconnect(context.Background())
+}
+
+func (c *client) Name() string {
+	return c.name
+}
+
+func (c *client) Monitor(ctx context.Context) error {
+	return c.conn.Ping(ctx, nil)
+}
+
+func (c *client) CreateCollection(ctx context.Context, opts *CreateCollectionOptions) error {
+	db := c.conn.Database(opts.DbName)
+
+	createOpts := options.CreateCollection()
+	if opts.Capped {
+		createOpts.SetCapped(true).SetSizeInBytes(opts.SizeInBytes)
+	}
+	if opts.ChangeStreamPreAndPostImages {
+		createOpts.SetChangeStreamPreAndPostImages(bson.M{"enabled": true})
+	}
+
+	if err := db.CreateCollection(ctx, opts.CollName, createOpts); err != nil {
+		return fmt.Errorf("failed to create collection %s.%s: %w", opts.DbName, opts.CollName, err)
+	}
+
+	return nil
+}
+
+func (c *client) Migrate(ctx context.Context, opts *MigrateOptions) error {
+	db := newMigrateDatabase(c.conn.Database(opts.DbName))
+	runner := migrate.NewRunner(migrate.ResumeTokenClusterTimeIndex(opts.CollNames))
+
+	return runner.Run(ctx, db)
+}
+
+func (c *client) WatchCollection(ctx context.Context, opts *WatchCollectionOptions) error {
+	watched := c.conn.Database(opts.WatchedDbName).Collection(opts.WatchedCollName)
+	tokens := c.conn.Database(opts.ResumeTokensDbName).Collection(opts.ResumeTokensCollName)
+
+	cs, err := c.openChangeStream(ctx, watched, tokens, opts)
+	if err != nil {
+		return err
+	}
+
+	go c.watchLoop(ctx, watched, tokens, opts, cs)
+
+	return nil
+}
+
+// openChangeStream resolves resume options against the persisted resume
+// token and opens a change stream on watched with them.
+func (c *client) openChangeStream(ctx context.Context, watched, tokens *mongo.Collection, opts *WatchCollectionOptions) (*mongo.ChangeStream, error) {
+	csOpts, err := c.resumeOptions(ctx, tokens, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve resume options for %s.%s: %w", opts.WatchedDbName, opts.WatchedCollName, err)
+	}
+
+	cs, err := watched.Watch(ctx, opts.Pipeline, csOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open change stream on %s.%s: %w", opts.WatchedDbName, opts.WatchedCollName, err)
+	}
+
+	return cs, nil
+}
+
+// watchRetryDelay bounds how quickly watchLoop reopens a change stream after
+// a fatal, non-context error, so a persistently failing server doesn't spin
+// the loop hot.
+const watchRetryDelay = 1 * time.Second
+
+// watchLoop drives cs until it's exhausted, then keeps reopening a fresh
+// change stream (re-resolving resume options, and so the MaxTokenAge
+// fallback, each time) and driving that one too, until ctx is done. This is
+// what lets a fatal, non-resumable stream error such as
+// ChangeStreamHistoryLost be recovered from instead of abandoning the
+// collection for the lifetime of the process.
+func (c *client) watchLoop(ctx context.Context, watched, tokens *mongo.Collection, opts *WatchCollectionOptions, cs *mongo.ChangeStream) {
+	for {
+		c.consumeChangeStream(ctx, cs, tokens, opts)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		var err error
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryDelay):
+			}
+
+			cs, err = c.openChangeStream(ctx, watched, tokens, opts)
+			if err == nil {
+				break
+			}
+			c.logger.Error("failed to reopen change stream, retrying", "db", opts.WatchedDbName, "coll", opts.WatchedCollName, "error", err)
+		}
+	}
+}
+
+// resumeTokenDoc is the shape of a document persisted in a resume tokens
+// collection, one per change event processed.
+type resumeTokenDoc struct {
+	Token       bson.Raw            `bson:"token"`
+	ClusterTime primitive.Timestamp `bson:"clusterTime"`
+}
+
+// resumeOptions builds the ChangeStreamOptions Watch should resume with,
+// honoring opts.ResumeMode and falling back (with a structured log event) to
+// starting at now minus opts.MaxTokenAge if the persisted token has aged
+// past it.
+func (c *client) resumeOptions(ctx context.Context, tokens *mongo.Collection, opts *WatchCollectionOptions) (*options.ChangeStreamOptions, error) {
+	csOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	if opts.ResumeMode == ResumeModeStartAtOperationTime {
+		csOpts.SetStartAtOperationTime(&opts.StartAtOperationTime)
+		return csOpts, nil
+	}
+
+	var doc resumeTokenDoc
+	err := tokens.FindOne(ctx, bson.D{}, options.FindOne().SetSort(bson.D{{Key: "clusterTime", Value: -1}})).Decode(&doc)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return csOpts, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to look up latest resume token in %s.%s: %w", tokens.Database().Name(), tokens.Name(), err)
+	}
+
+	tokenAge := time.Since(time.Unix(int64(doc.ClusterTime.T), 0))
+	if opts.MaxTokenAge > 0 && tokenAge > opts.MaxTokenAge {
+		c.logger.Warn("resume token too old, falling back to startAtOperationTime",
+			"db", opts.WatchedDbName, "coll", opts.WatchedCollName, "tokenAge", tokenAge, "maxTokenAge", opts.MaxTokenAge)
+		fallback := primitive.Timestamp{T: uint32(time.Now().Add(-opts.MaxTokenAge).Unix())}
+		csOpts.SetStartAtOperationTime(&fallback)
+		return csOpts, nil
+	}
+
+	if opts.ResumeMode == ResumeModeStartAfter {
+		csOpts.SetStartAfter(doc.Token)
+	} else {
+		csOpts.SetResumeAfter(doc.Token)
+	}
+
+	return csOpts, nil
+}
+
+// consumeChangeStream reads events off cs until it's exhausted (ctx
+// cancelled or an unresumable error), forwarding each to
+// opts.ChangeEventHandler and persisting its resume token in tokens
+// afterwards.
+func (c *client) consumeChangeStream(ctx context.Context, cs *mongo.ChangeStream, tokens *mongo.Collection, opts *WatchCollectionOptions) {
+	defer cs.Close(context.Background())
+
+	for cs.Next(ctx) {
+		var event bson.M
+		if err := cs.Decode(&event); err != nil {
+			c.logger.Error("failed to decode change event", "db", opts.WatchedDbName, "coll", opts.WatchedCollName, "error", err)
+			continue
+		}
+
+		subj, msgId := changeEventSubjectAndMsgID(opts.StreamName, event)
+
+		data, err := bson.MarshalExtJSON(event, false, false)
+		if err != nil {
+			c.logger.Error("failed to marshal change event", "db", opts.WatchedDbName, "coll", opts.WatchedCollName, "error", err)
+			continue
+		}
+
+		if err := opts.ChangeEventHandler(ctx, subj, msgId, data); err != nil {
+			c.logger.Error("failed to publish change event", "db", opts.WatchedDbName, "coll", opts.WatchedCollName, "error", err)
+			continue
+		}
+
+		if _, err := tokens.InsertOne(ctx, bson.D{
+			{Key: "token", Value: cs.ResumeToken()},
+			{Key: "clusterTime", Value: event["clusterTime"]},
+		}); err != nil {
+			c.logger.Error("failed to persist resume token", "db", opts.WatchedDbName, "coll", opts.WatchedCollName, "error", err)
+		}
+	}
+
+	if err := cs.Err(); err != nil && ctx.Err() == nil {
+		c.logger.Error("change stream closed with an error", "db", opts.WatchedDbName, "coll", opts.WatchedCollName, "error", err)
+	}
+}
+
+// changeEventSubjectAndMsgID derives the subject a change event is
+// published under and the dedup key downstream backends use for it: the
+// stream name qualified with the operation type, and the watched document's
+// _id (from the event's documentKey, not the event's own resume token)
+// serialized as extended JSON.
+func changeEventSubjectAndMsgID(streamName string, event bson.M) (subj, msgId string) {
+	subj = streamName
+	if opType, ok := event["operationType"].(string); ok && opType != "" {
+		subj = streamName + "." + opType
+	}
+
+	if documentKey, ok := event["documentKey"].(bson.M); ok {
+		if id, ok := documentKey["_id"]; ok {
+			if b, err := bson.MarshalExtJSON(id, false, false); err == nil {
+				msgId = string(b)
+			}
+		}
+	}
+
+	return subj, msgId
+}
@@ -0,0 +1,230 @@
+// Package nats wraps a NATS JetStream connection with the narrow surface
+// the connector needs: ensuring a stream exists and publishing deduplicated
+// messages to it.
+package nats
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nkeys"
+)
+
+// Client is the interface the connector depends on, satisfied by the real
+// JetStream-backed client in production and by a mock in tests.
+type Client interface {
+	Close() error
+	Name() string
+	Monitor(ctx context.Context) error
+	AddStream(ctx context.Context, opts *AddStreamOptions) error
+	Publish(ctx context.Context, opts *PublishOptions) error
+}
+
+// AddStreamOptions describes a JetStream stream to create if it doesn't
+// already exist.
+type AddStreamOptions struct {
+	StreamName string
+}
+
+// PublishOptions describes a single message to publish. MsgId is used as
+// the JetStream dedup key so redeliveries of the same change event don't
+// produce duplicate messages.
+type PublishOptions struct {
+	Subj  string
+	MsgId string
+	Data  []byte
+}
+
+type client struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+	name string
+}
+
+// TLSConfig configures mTLS for the NATS connection.
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// Option configures the NATS client built by New.
+type Option func(*clientOptions) error
+
+type clientOptions struct {
+	tls       *TLSConfig
+	token     string
+	credsFile string
+	nkeySeed  []byte
+}
+
+// WithTLS enables TLS on the connection, optionally with a client
+// certificate for mTLS.
+func WithTLS(cfg TLSConfig) Option {
+	return func(o *clientOptions) error {
+		o.tls = &cfg
+		return nil
+	}
+}
+
+// WithToken authenticates the connection with a plain token.
+func WithToken(token string) Option {
+	return func(o *clientOptions) error {
+		o.token = token
+		return nil
+	}
+}
+
+// WithCredsFile authenticates the connection with a JWT/NKey .creds file,
+// as produced by `nsc`.
+func WithCredsFile(path string) Option {
+	return func(o *clientOptions) error {
+		o.credsFile = path
+		return nil
+	}
+}
+
+// WithNKeySeed authenticates the connection with a raw NKey seed.
+func WithNKeySeed(seed []byte) Option {
+	return func(o *clientOptions) error {
+		o.nkeySeed = seed
+		return nil
+	}
+}
+
+// New connects to the NATS server at url and opens a JetStream context.
+func New(url string, opts ...Option) (Client, error) {
+	co := &clientOptions{}
+	for _, opt := range opts {
+		if err := opt(co); err != nil {
+			return nil, err
+		}
+	}
+
+	var connOpts []nats.Option
+
+	if co.tls != nil {
+		tlsConfig, err := buildTLSConfig(co.tls)
+		if err != nil {
+			return nil, err
+		}
+		connOpts = append(connOpts, nats.Secure(tlsConfig))
+	}
+
+	if co.token != "" {
+		connOpts = append(connOpts, nats.Token(co.token))
+	}
+
+	if co.credsFile != "" {
+		connOpts = append(connOpts, nats.UserCredentials(co.credsFile))
+	}
+
+	if len(co.nkeySeed) > 0 {
+		pub, sigCb, err := nkeySignatureHandler(co.nkeySeed)
+		if err != nil {
+			return nil, err
+		}
+		connOpts = append(connOpts, nats.Nkey(pub, sigCb))
+	}
+
+	conn, err := nats.Connect(url, connOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	return &client{conn: conn, js: js, name: "nats"}, nil
+}
+
+func buildTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CAFile != "" {
+		caCert, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read nats CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse nats CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load nats client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func nkeySignatureHandler(seed []byte) (string, nats.SignatureHandler, error) {
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse nats nkey seed: %w", err)
+	}
+
+	pub, err := kp.PublicKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to derive nats nkey public key: %w", err)
+	}
+
+	return pub, func(nonce []byte) ([]byte, error) {
+		return kp.Sign(nonce)
+	}, nil
+}
+
+func (c *client) Close() error {
+	c.conn.Close()
+	return nil
+}
+
+func (c *client) Name() string {
+	return c.name
+}
+
+func (c *client) Monitor(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *client) AddStream(ctx context.Context, opts *AddStreamOptions) error {
+	_, err := c.js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     opts.StreamName,
+		Subjects: []string{opts.StreamName + ".*"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add stream %s: %w", opts.StreamName, err)
+	}
+
+	return nil
+}
+
+func (c *client) Publish(ctx context.Context, opts *PublishOptions) error {
+	msg := &nats.Msg{
+		Subject: opts.Subj,
+		Data:    opts.Data,
+		Header:  nats.Header{nats.MsgIdHdr: []string{opts.MsgId}},
+	}
+
+	if _, err := c.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish message to %s: %w", opts.Subj, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// publishTimeout bounds how long Publish waits for the broker to
+// acknowledge a QoS 1/2 publish before giving up.
+const publishTimeout = 10 * time.Second
+
+// mqttBackend publishes change events over MQTT at QoS 1, with retained
+// messages disabled so a subscriber only ever sees events published after
+// it connects.
+type mqttBackend struct {
+	client mqtt.Client
+}
+
+// NewMqttBackend connects to the broker at brokerUrl.
+func NewMqttBackend(brokerUrl string) (Backend, error) {
+	opts := mqtt.NewClientOptions().AddBroker(brokerUrl)
+	client := mqtt.NewClient(opts)
+
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("failed to connect to mqtt broker: %w", token.Error())
+	}
+
+	return &mqttBackend{client: client}, nil
+}
+
+func (b *mqttBackend) EnsureTopic(_ context.Context, _ TopicOptions) error {
+	// MQTT has no concept of a pre-declared topic; publishers and
+	// subscribers simply agree on a topic string out of band.
+	return nil
+}
+
+func (b *mqttBackend) Publish(_ context.Context, opts PublishOptions) error {
+	const (
+		qos      = 1
+		retained = false
+	)
+
+	token := b.client.Publish(opts.Subj, qos, retained, opts.Data)
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out publishing to %s", opts.Subj)
+	}
+
+	return token.Error()
+}
+
+func (b *mqttBackend) Close() error {
+	b.client.Disconnect(250)
+	return nil
+}
+
+func (b *mqttBackend) Monitor(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
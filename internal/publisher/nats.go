@@ -0,0 +1,38 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/context-labs/mongodb-nats-connector/internal/nats"
+)
+
+// natsBackend adapts the connector's original JetStream-only client to the
+// Backend interface.
+type natsBackend struct {
+	client nats.Client
+}
+
+// NewNatsBackend wraps an existing NATS JetStream client as a Backend.
+func NewNatsBackend(client nats.Client) Backend {
+	return &natsBackend{client: client}
+}
+
+func (b *natsBackend) EnsureTopic(ctx context.Context, opts TopicOptions) error {
+	return b.client.AddStream(ctx, &nats.AddStreamOptions{StreamName: opts.Name})
+}
+
+func (b *natsBackend) Publish(ctx context.Context, opts PublishOptions) error {
+	return b.client.Publish(ctx, &nats.PublishOptions{
+		Subj:  opts.Subj,
+		MsgId: opts.MsgId,
+		Data:  opts.Data,
+	})
+}
+
+func (b *natsBackend) Close() error {
+	return b.client.Close()
+}
+
+func (b *natsBackend) Monitor(ctx context.Context) error {
+	return b.client.Monitor(ctx)
+}
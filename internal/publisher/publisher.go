@@ -0,0 +1,31 @@
+// Package publisher abstracts the message broker change events are
+// forwarded to. The connector itself only depends on the Backend
+// interface; concrete adapters live alongside it in this package.
+package publisher
+
+import "context"
+
+// Backend is the interface each supported message broker adapter
+// implements. It mirrors the surface the connector originally hard-coded
+// to NATS JetStream, so swapping brokers doesn't touch the connector.
+type Backend interface {
+	EnsureTopic(ctx context.Context, opts TopicOptions) error
+	Publish(ctx context.Context, opts PublishOptions) error
+	Close() error
+	Monitor(ctx context.Context) error
+}
+
+// TopicOptions describes the topic, stream, or subject a backend should
+// ensure exists before any messages are published to it.
+type TopicOptions struct {
+	Name string
+}
+
+// PublishOptions describes a single message to publish. MsgId is used by
+// backends that support idempotent delivery to deduplicate redeliveries of
+// the same change event, keyed on the Mongo document's _id.
+type PublishOptions struct {
+	Subj  string
+	MsgId string
+	Data  []byte
+}
@@ -0,0 +1,255 @@
+package connector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/context-labs/mongodb-nats-connector/internal/mongo"
+	"github.com/context-labs/mongodb-nats-connector/internal/publisher"
+)
+
+// changeStreamPipelineStages are the aggregation stages MongoDB allows in a
+// change stream pipeline. Anything else (e.g. $out, $merge) either makes no
+// sense against a stream of events or isn't supported by the server.
+var changeStreamPipelineStages = map[string]struct{}{
+	"$addFields":   {},
+	"$set":         {},
+	"$match":       {},
+	"$project":     {},
+	"$replaceRoot": {},
+	"$replaceWith": {},
+	"$redact":      {},
+	"$unset":       {},
+}
+
+// collection holds the fully-resolved configuration for a single watched
+// MongoDB collection: where its change events come from, where its resume
+// tokens are persisted, and which topic, stream, or subject on which
+// backend its events are published to.
+type collection struct {
+	dbName   string
+	collName string
+
+	changeStreamPreAndPostImages bool
+
+	tokensDbName          string
+	tokensCollName        string
+	tokensCollCapped      bool
+	tokensCollSizeInBytes int64
+
+	streamName  string
+	backendName string
+	publisher   publisher.Backend
+
+	pipeline mongo.Pipeline
+
+	resumeStrategy ResumeStrategy
+	maxTokenAge    time.Duration
+}
+
+// ResumeStrategy selects how a watched collection's change stream resumes
+// after a restart. The zero value is ResumeAfter, the default.
+type ResumeStrategy struct {
+	mode                 mongo.ResumeMode
+	startAtOperationTime primitive.Timestamp
+}
+
+// ResumeAfter resumes from the persisted token using resumeAfter, rejecting
+// the token if its event has fallen out of the oplog window. This is the
+// default.
+func ResumeAfter() ResumeStrategy {
+	return ResumeStrategy{mode: mongo.ResumeModeAfter}
+}
+
+// StartAfter resumes from the persisted token using startAfter, which
+// (unlike ResumeAfter) tolerates a token whose event was an invalidate
+// event, such as a collection drop or rename.
+func StartAfter() ResumeStrategy {
+	return ResumeStrategy{mode: mongo.ResumeModeStartAfter}
+}
+
+// StartAtOperationTime starts the change stream at ts, ignoring any
+// persisted resume token.
+func StartAtOperationTime(ts primitive.Timestamp) ResumeStrategy {
+	return ResumeStrategy{mode: mongo.ResumeModeStartAtOperationTime, startAtOperationTime: ts}
+}
+
+// CollectionOption configures a collection registered via WithCollection.
+type CollectionOption func(*collection) error
+
+// WithCollection registers a MongoDB collection to watch for changes and
+// publish to a message broker (NATS JetStream unless WithBackend or
+// WithPublisher says otherwise). dbName and collName identify the collection to
+// watch; by default resume tokens are stored uncapped in a same-named
+// collection in the "resume-tokens" database, and the stream name is the
+// upper-cased collection name.
+func WithCollection(dbName, collName string, opts ...CollectionOption) Option {
+	return func(o *options) error {
+		if dbName == "" {
+			return ErrDbNameMissing
+		}
+		if collName == "" {
+			return ErrCollNameMissing
+		}
+
+		c := &collection{
+			dbName:         dbName,
+			collName:       collName,
+			tokensDbName:   "resume-tokens",
+			tokensCollName: collName,
+			streamName:     strings.ToUpper(collName),
+		}
+
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+
+		if c.dbName == c.tokensDbName && c.collName == c.tokensCollName {
+			return ErrInvalidDbAndCollNames
+		}
+
+		o.collections = append(o.collections, c)
+		return nil
+	}
+}
+
+// WithChangeStreamPreAndPostImages enables pre- and post-images on the
+// watched collection's change stream, so updates carry the full
+// before/after document instead of just the delta.
+func WithChangeStreamPreAndPostImages() CollectionOption {
+	return func(c *collection) error {
+		c.changeStreamPreAndPostImages = true
+		return nil
+	}
+}
+
+// WithTokensDbName overrides the database resume tokens are stored in.
+func WithTokensDbName(dbName string) CollectionOption {
+	return func(c *collection) error {
+		c.tokensDbName = dbName
+		return nil
+	}
+}
+
+// WithTokensCollName overrides the collection resume tokens are stored in.
+func WithTokensCollName(collName string) CollectionOption {
+	return func(c *collection) error {
+		c.tokensCollName = collName
+		return nil
+	}
+}
+
+// WithTokensCollCapped caps the resume tokens collection at sizeInBytes,
+// so it self-trims instead of growing without bound.
+func WithTokensCollCapped(sizeInBytes int64) CollectionOption {
+	return func(c *collection) error {
+		if sizeInBytes <= 0 {
+			return ErrInvalidCollSizeInBytes
+		}
+
+		c.tokensCollCapped = true
+		c.tokensCollSizeInBytes = sizeInBytes
+		return nil
+	}
+}
+
+// WithStreamName overrides the topic, stream, or subject change events are
+// published to.
+func WithStreamName(streamName string) CollectionOption {
+	return func(c *collection) error {
+		c.streamName = streamName
+		return nil
+	}
+}
+
+// WithBackend selects which message broker change events are published to.
+// Supported values are "nats" (the default), "kafka", and "mqtt".
+func WithBackend(name string) CollectionOption {
+	return func(c *collection) error {
+		switch name {
+		case "nats", "kafka", "mqtt":
+			c.backendName = name
+			return nil
+		default:
+			return ErrUnsupportedBackend
+		}
+	}
+}
+
+// WithPublisher injects a pre-built publisher.Backend, bypassing WithBackend
+// and the connector's own broker configuration. It's primarily useful for
+// tests and for backends the connector doesn't build in.
+func WithPublisher(backend publisher.Backend) CollectionOption {
+	return func(c *collection) error {
+		c.publisher = backend
+		return nil
+	}
+}
+
+// WithChangeStreamPipeline appends stages to the aggregation pipeline the
+// collection's change stream is opened with, so a server-side $match or
+// $project can filter or reshape events before they're ever published.
+// Stages outside what MongoDB allows in a change stream pipeline (e.g.
+// $out, $merge) are rejected.
+func WithChangeStreamPipeline(pipeline mongo.Pipeline) CollectionOption {
+	return func(c *collection) error {
+		for _, stage := range pipeline {
+			if len(stage) == 0 {
+				continue
+			}
+			if _, ok := changeStreamPipelineStages[stage[0].Key]; !ok {
+				return fmt.Errorf("%w: %q", ErrUnsupportedPipelineStage, stage[0].Key)
+			}
+		}
+
+		c.pipeline = append(c.pipeline, pipeline...)
+		return nil
+	}
+}
+
+// WithChangeStreamOperationTypes is a convenience over
+// WithChangeStreamPipeline that appends a $match stage filtering on the
+// change event's operationType, e.g. WithChangeStreamOperationTypes("insert", "update").
+func WithChangeStreamOperationTypes(types ...string) CollectionOption {
+	ops := make(bson.A, len(types))
+	for i, t := range types {
+		ops[i] = t
+	}
+
+	stage := bson.D{{Key: "$match", Value: bson.D{
+		{Key: "operationType", Value: bson.D{{Key: "$in", Value: ops}}},
+	}}}
+
+	return WithChangeStreamPipeline(mongo.Pipeline{stage})
+}
+
+// WithResumeStrategy overrides how the collection's change stream resumes
+// after a restart. The default is ResumeAfter.
+func WithResumeStrategy(strategy ResumeStrategy) CollectionOption {
+	return func(c *collection) error {
+		c.resumeStrategy = strategy
+		return nil
+	}
+}
+
+// WithMaxTokenAge bounds how long a persisted resume token may be trusted.
+// A token older than d is abandoned in favor of starting the change stream
+// at now minus d rather than risk a resumeAfter/startAfter call
+// failing against a server that's already rolled the token's position out
+// of its oplog.
+func WithMaxTokenAge(d time.Duration) CollectionOption {
+	return func(c *collection) error {
+		if d <= 0 {
+			return ErrInvalidMaxTokenAge
+		}
+
+		c.maxTokenAge = d
+		return nil
+	}
+}
@@ -0,0 +1,391 @@
+// Package connector watches MongoDB collections for changes and publishes
+// them as messages to a broker backend (NATS JetStream by default, or Kafka
+// or MQTT), persisting resume tokens so delivery survives restarts.
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/context-labs/mongodb-nats-connector/internal/mongo"
+	"github.com/context-labs/mongodb-nats-connector/internal/nats"
+	"github.com/context-labs/mongodb-nats-connector/internal/publisher"
+)
+
+// options holds the fully-resolved configuration assembled by New from the
+// supplied Option values.
+type options struct {
+	logLevel slog.Level
+
+	mongoUri    string
+	mongoClient mongo.Client
+
+	natsUrl    string
+	natsClient nats.Client
+
+	natsTLS       *TLSConfig
+	natsToken     string
+	natsCredsFile string
+	natsNKeySeed  []byte
+
+	kafkaBrokers  []string
+	mqttBrokerUrl string
+
+	migrationsDisabled bool
+
+	ctx  context.Context
+	stop context.CancelFunc
+
+	serverAddr string
+
+	collections []*collection
+}
+
+// Option configures a Connector created via New.
+type Option func(*options) error
+
+// Connector watches the collections registered via WithCollection and
+// forwards their change events to each collection's publisher backend.
+type Connector struct {
+	options  *options
+	logger   *slog.Logger
+	server   *http.Server
+	backends []publisher.Backend
+}
+
+// New builds a Connector from the given options, validating them eagerly so
+// misconfiguration is reported before anything connects to MongoDB or NATS.
+func New(opts ...Option) (*Connector, error) {
+	o := &options{
+		logLevel: slog.LevelInfo,
+	}
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.ctx != nil {
+		o.ctx, o.stop = context.WithCancel(o.ctx)
+	} else {
+		o.ctx, o.stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: o.logLevel}))
+
+	if o.mongoClient == nil {
+		mongoClient, err := mongo.New(o.ctx, o.mongoUri, logger)
+		if err != nil {
+			o.stop()
+			return nil, err
+		}
+		o.mongoClient = mongoClient
+	}
+
+	if o.natsClient == nil {
+		natsClient, err := nats.New(o.natsUrl, natsClientOptions(o)...)
+		if err != nil {
+			o.stop()
+			return nil, err
+		}
+		o.natsClient = natsClient
+	}
+
+	return &Connector{
+		options: o,
+		logger:  logger,
+		server:  &http.Server{Addr: o.serverAddr},
+	}, nil
+}
+
+// natsClientOptions translates the connector's NATS transport/auth
+// settings into internal/nats client options.
+func natsClientOptions(o *options) []nats.Option {
+	var opts []nats.Option
+
+	if o.natsTLS != nil {
+		opts = append(opts, nats.WithTLS(nats.TLSConfig{
+			CAFile:             o.natsTLS.CAFile,
+			CertFile:           o.natsTLS.CertFile,
+			KeyFile:            o.natsTLS.KeyFile,
+			InsecureSkipVerify: o.natsTLS.InsecureSkipVerify,
+		}))
+	}
+
+	if o.natsToken != "" {
+		opts = append(opts, nats.WithToken(o.natsToken))
+	}
+
+	if o.natsCredsFile != "" {
+		opts = append(opts, nats.WithCredsFile(o.natsCredsFile))
+	}
+
+	if len(o.natsNKeySeed) > 0 {
+		opts = append(opts, nats.WithNKeySeed(o.natsNKeySeed))
+	}
+
+	return opts
+}
+
+// WithLogLevel sets the logger's level. Supported values are "debug",
+// "info", "warn", and "error"; anything else falls back to "info".
+func WithLogLevel(level string) Option {
+	return func(o *options) error {
+		switch strings.ToLower(level) {
+		case "debug":
+			o.logLevel = slog.LevelDebug
+		case "warn":
+			o.logLevel = slog.LevelWarn
+		case "error":
+			o.logLevel = slog.LevelError
+		default:
+			o.logLevel = slog.LevelInfo
+		}
+		return nil
+	}
+}
+
+// WithMongoUri sets the MongoDB connection string used when no client has
+// been injected via withMongoClient.
+func WithMongoUri(uri string) Option {
+	return func(o *options) error {
+		o.mongoUri = uri
+		return nil
+	}
+}
+
+// withMongoClient injects a pre-built MongoDB client, bypassing WithMongoUri.
+// It's unexported because it exists for tests to avoid dialing a real
+// MongoDB instance.
+func withMongoClient(client mongo.Client) Option {
+	return func(o *options) error {
+		o.mongoClient = client
+		return nil
+	}
+}
+
+// WithNatsUrl sets the NATS connection string used when no client has been
+// injected via withNatsClient.
+func WithNatsUrl(url string) Option {
+	return func(o *options) error {
+		o.natsUrl = url
+		return nil
+	}
+}
+
+// withNatsClient injects a pre-built NATS client, bypassing WithNatsUrl. It's
+// unexported because it exists for tests to avoid dialing a real NATS
+// instance.
+func withNatsClient(client nats.Client) Option {
+	return func(o *options) error {
+		o.natsClient = client
+		return nil
+	}
+}
+
+// WithKafkaBrokers sets the seed brokers used to resolve collections
+// registered with WithBackend("kafka").
+func WithKafkaBrokers(brokers []string) Option {
+	return func(o *options) error {
+		o.kafkaBrokers = brokers
+		return nil
+	}
+}
+
+// WithMqttBrokerUrl sets the broker URL used to resolve collections
+// registered with WithBackend("mqtt").
+func WithMqttBrokerUrl(url string) Option {
+	return func(o *options) error {
+		o.mqttBrokerUrl = url
+		return nil
+	}
+}
+
+// WithMigrationsDisabled skips running the resume-token schema migrations
+// in Run, for deployments that manage schema externally.
+func WithMigrationsDisabled() Option {
+	return func(o *options) error {
+		o.migrationsDisabled = true
+		return nil
+	}
+}
+
+// WithContext overrides the base context the Connector runs under. By
+// default, Run is cancelled on SIGINT/SIGTERM.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) error {
+		o.ctx = ctx
+		return nil
+	}
+}
+
+// WithServerAddr sets the address of the health check server started by
+// Run.
+func WithServerAddr(addr string) Option {
+	return func(o *options) error {
+		o.serverAddr = addr
+		return nil
+	}
+}
+
+// Run creates the watched and resume-token collections, ensures each
+// collection's topic exists on its publisher backend, and starts watching
+// each registered collection for changes. It blocks until its context is
+// cancelled or an unrecoverable error occurs, then closes the MongoDB client
+// and every publisher backend it resolved before returning. The NATS client
+// is closed only through a NATS backend that wraps it.
+func (c *Connector) Run() error {
+	ctx := c.options.ctx
+	defer c.options.stop()
+
+	if err := c.setUp(ctx); err != nil {
+		return errors.Join(err, c.closeAll())
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := c.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err := <-errCh:
+		c.logger.Error("server error", "error", err)
+	}
+
+	_ = c.server.Close()
+
+	return errors.Join(ctx.Err(), c.closeAll())
+}
+
+// setUp creates the watched and resume-token collections, runs any pending
+// resume-token migrations, and starts watching each registered collection
+// for changes, appending every backend it resolves to c.backends as it
+// goes so a failure partway through still leaves c.closeAll able to close
+// what was opened.
+func (c *Connector) setUp(ctx context.Context) error {
+	tokensCollsByDb := map[string][]string{}
+
+	for _, coll := range c.options.collections {
+		if err := c.options.mongoClient.CreateCollection(ctx, &mongo.CreateCollectionOptions{
+			DbName:                       coll.dbName,
+			CollName:                     coll.collName,
+			ChangeStreamPreAndPostImages: coll.changeStreamPreAndPostImages,
+		}); err != nil {
+			return fmt.Errorf("failed to create collection %s.%s: %w", coll.dbName, coll.collName, err)
+		}
+
+		if err := c.options.mongoClient.CreateCollection(ctx, &mongo.CreateCollectionOptions{
+			DbName:      coll.tokensDbName,
+			CollName:    coll.tokensCollName,
+			Capped:      coll.tokensCollCapped,
+			SizeInBytes: coll.tokensCollSizeInBytes,
+		}); err != nil {
+			return fmt.Errorf("failed to create resume tokens collection %s.%s: %w", coll.tokensDbName, coll.tokensCollName, err)
+		}
+
+		tokensCollsByDb[coll.tokensDbName] = append(tokensCollsByDb[coll.tokensDbName], coll.tokensCollName)
+	}
+
+	if !c.options.migrationsDisabled {
+		dbNames := make([]string, 0, len(tokensCollsByDb))
+		for dbName := range tokensCollsByDb {
+			dbNames = append(dbNames, dbName)
+		}
+		sort.Strings(dbNames)
+
+		for _, dbName := range dbNames {
+			if err := c.options.mongoClient.Migrate(ctx, &mongo.MigrateOptions{
+				DbName:    dbName,
+				CollNames: tokensCollsByDb[dbName],
+			}); err != nil {
+				return fmt.Errorf("failed to run migrations for %s: %w", dbName, err)
+			}
+		}
+	}
+
+	for _, coll := range c.options.collections {
+		backend, err := c.resolvePublisher(coll)
+		if err != nil {
+			return fmt.Errorf("failed to resolve publisher for %s.%s: %w", coll.dbName, coll.collName, err)
+		}
+		c.backends = append(c.backends, backend)
+
+		if err := backend.EnsureTopic(ctx, publisher.TopicOptions{Name: coll.streamName}); err != nil {
+			return fmt.Errorf("failed to ensure topic %s: %w", coll.streamName, err)
+		}
+
+		if err := c.options.mongoClient.WatchCollection(ctx, &mongo.WatchCollectionOptions{
+			WatchedDbName:          coll.dbName,
+			WatchedCollName:        coll.collName,
+			ResumeTokensDbName:     coll.tokensDbName,
+			ResumeTokensCollName:   coll.tokensCollName,
+			ResumeTokensCollCapped: coll.tokensCollCapped,
+			StreamName:             coll.streamName,
+			Pipeline:               coll.pipeline,
+			ChangeEventHandler:     c.publishChangeEvent(backend),
+			ResumeMode:             coll.resumeStrategy.mode,
+			StartAtOperationTime:   coll.resumeStrategy.startAtOperationTime,
+			MaxTokenAge:            coll.maxTokenAge,
+		}); err != nil {
+			return fmt.Errorf("failed to watch collection %s.%s: %w", coll.dbName, coll.collName, err)
+		}
+	}
+
+	return nil
+}
+
+// closeAll closes the MongoDB client and every backend resolved so far,
+// joining their errors. It's safe to call whether setUp ran to completion
+// or failed partway through.
+func (c *Connector) closeAll() error {
+	errs := []error{c.options.mongoClient.Close()}
+	for _, backend := range c.backends {
+		errs = append(errs, backend.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolvePublisher returns the publisher.Backend a collection publishes its
+// change events to: an explicitly injected one if WithPublisher was used,
+// otherwise one built from its WithBackend selection.
+func (c *Connector) resolvePublisher(coll *collection) (publisher.Backend, error) {
+	if coll.publisher != nil {
+		return coll.publisher, nil
+	}
+
+	switch coll.backendName {
+	case "", "nats":
+		return publisher.NewNatsBackend(c.options.natsClient), nil
+	case "kafka":
+		return publisher.NewKafkaBackend(c.options.kafkaBrokers)
+	case "mqtt":
+		return publisher.NewMqttBackend(c.options.mqttBrokerUrl)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedBackend, coll.backendName)
+	}
+}
+
+// publishChangeEvent returns a ChangeEventHandler that forwards a change
+// event read off a watched collection to backend.
+func (c *Connector) publishChangeEvent(backend publisher.Backend) mongo.ChangeEventHandler {
+	return func(ctx context.Context, subj, msgId string, data []byte) error {
+		return backend.Publish(ctx, publisher.PublishOptions{
+			Subj:  subj,
+			MsgId: msgId,
+			Data:  data,
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
@@ -12,9 +13,12 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/context-labs/mongodb-nats-connector/internal/mongo"
 	"github.com/context-labs/mongodb-nats-connector/internal/nats"
+	"github.com/context-labs/mongodb-nats-connector/internal/publisher"
 )
 
 func TestNew(t *testing.T) {
@@ -206,6 +210,117 @@ func TestNew(t *testing.T) {
 		require.Nil(t, conn)
 		require.EqualError(t, err, ErrInvalidDbAndCollNames.Error())
 	})
+	t.Run("should create connector with a change stream pipeline", func(t *testing.T) {
+		var (
+			mongoClient = &mockMongoClient{}
+			natsClient  = &mockNatsClient{}
+			dbName      = "connector-db"
+			collName    = "coll1"
+		)
+
+		conn, err := New(
+			withMongoClient(mongoClient),
+			withNatsClient(natsClient),
+			WithCollection(dbName, collName,
+				WithChangeStreamOperationTypes("insert", "update"),
+			),
+		)
+
+		require.NoError(t, err)
+		require.Len(t, conn.options.collections, 1)
+		require.Equal(t, mongo.Pipeline{
+			bson.D{{Key: "$match", Value: bson.D{
+				{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update"}}}},
+			}}},
+		}, conn.options.collections[0].pipeline)
+	})
+	t.Run("should return error cause change stream pipeline has an unsupported stage", func(t *testing.T) {
+		conn, err := New(
+			WithCollection("test-db", "test-coll",
+				WithChangeStreamPipeline(mongo.Pipeline{bson.D{{Key: "$merge", Value: "other-coll"}}}),
+			),
+		)
+
+		require.Nil(t, conn)
+		require.EqualError(t, err, ErrUnsupportedPipelineStage.Error()+`: "$merge"`)
+	})
+	t.Run("should create connector with a resume strategy", func(t *testing.T) {
+		var (
+			mongoClient = &mockMongoClient{}
+			natsClient  = &mockNatsClient{}
+			dbName      = "connector-db"
+			collName    = "coll1"
+			ts          = primitive.Timestamp{T: 1234, I: 1}
+		)
+
+		conn, err := New(
+			withMongoClient(mongoClient),
+			withNatsClient(natsClient),
+			WithCollection(dbName, collName,
+				WithResumeStrategy(StartAtOperationTime(ts)),
+				WithMaxTokenAge(time.Hour),
+			),
+		)
+
+		require.NoError(t, err)
+		require.Len(t, conn.options.collections, 1)
+		require.Equal(t, StartAtOperationTime(ts), conn.options.collections[0].resumeStrategy)
+		require.Equal(t, time.Hour, conn.options.collections[0].maxTokenAge)
+	})
+	t.Run("should return error cause maxTokenAge is not positive", func(t *testing.T) {
+		conn, err := New(
+			WithCollection("test-db", "test-coll", WithMaxTokenAge(0)),
+		)
+
+		require.Nil(t, conn)
+		require.EqualError(t, err, ErrInvalidMaxTokenAge.Error())
+	})
+	t.Run("should return error cause nats cert file given without key file", func(t *testing.T) {
+		conn, err := New(
+			WithNatsTLS(TLSConfig{CertFile: "cert.pem"}),
+		)
+
+		require.Nil(t, conn)
+		require.EqualError(t, err, ErrInvalidNatsTLSConfig.Error())
+	})
+	t.Run("should return error cause nats key file given without cert file", func(t *testing.T) {
+		conn, err := New(
+			WithNatsTLS(TLSConfig{KeyFile: "key.pem"}),
+		)
+
+		require.Nil(t, conn)
+		require.EqualError(t, err, ErrInvalidNatsTLSConfig.Error())
+	})
+	t.Run("should configure nats auth from the environment", func(t *testing.T) {
+		t.Setenv("NATS_CA_FILE", "ca.pem")
+		t.Setenv("NATS_CERT_FILE", "cert.pem")
+		t.Setenv("NATS_KEY_FILE", "key.pem")
+		t.Setenv("NATS_TOKEN", "s3cr3t")
+		t.Setenv("NATS_CREDS_FILE", "nats.creds")
+
+		conn, err := New(
+			withMongoClient(&mockMongoClient{}),
+			withNatsClient(&mockNatsClient{}),
+			WithNatsAuthFromEnv(),
+		)
+
+		require.NoError(t, err)
+		require.Equal(t, &TLSConfig{CAFile: "ca.pem", CertFile: "cert.pem", KeyFile: "key.pem"}, conn.options.natsTLS)
+		require.Equal(t, "s3cr3t", conn.options.natsToken)
+		require.Equal(t, "nats.creds", conn.options.natsCredsFile)
+	})
+	t.Run("should leave nats auth untouched cause environment is empty", func(t *testing.T) {
+		conn, err := New(
+			withMongoClient(&mockMongoClient{}),
+			withNatsClient(&mockNatsClient{}),
+			WithNatsAuthFromEnv(),
+		)
+
+		require.NoError(t, err)
+		require.Nil(t, conn.options.natsTLS)
+		require.Empty(t, conn.options.natsToken)
+		require.Empty(t, conn.options.natsCredsFile)
+	})
 }
 
 func TestConnector_Run(t *testing.T) {
@@ -223,6 +338,8 @@ func TestConnector_Run(t *testing.T) {
 			subj            = "subj"
 			msgId           = "msgId"
 			data            = []byte("event")
+			resumeStrategy  = StartAfter()
+			maxTokenAge     = time.Hour
 		)
 		defer cancel()
 
@@ -237,9 +354,18 @@ func TestConnector_Run(t *testing.T) {
 				WithTokensCollName(tokensCollName),
 				WithTokensCollCapped(collSizeInBytes),
 				WithStreamName(streamName),
+				WithChangeStreamOperationTypes("insert", "update"),
+				WithResumeStrategy(resumeStrategy),
+				WithMaxTokenAge(maxTokenAge),
 			),
 		)
 
+		expectedPipeline := mongo.Pipeline{
+			bson.D{{Key: "$match", Value: bson.D{
+				{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update"}}}},
+			}}},
+		}
+
 		errCh := make(chan error)
 		go func() {
 			errCh <- conn.Run()
@@ -269,6 +395,15 @@ func TestConnector_Run(t *testing.T) {
 			}, 1*time.Second, 100*time.Millisecond)
 		})
 
+		t.Run("run resume-token migrations", func(t *testing.T) {
+			require.Eventually(t, func() bool {
+				return mongoClient.MigrationsWereRun(mongo.MigrateOptions{
+					DbName:    tokensDbName,
+					CollNames: []string{tokensCollName},
+				})
+			}, 1*time.Second, 100*time.Millisecond)
+		})
+
 		t.Run("add nats streams", func(t *testing.T) {
 			require.Eventually(t, func() bool {
 				return natsClient.StreamWasAdded(nats.AddStreamOptions{
@@ -286,6 +421,9 @@ func TestConnector_Run(t *testing.T) {
 					ResumeTokensCollName:   tokensCollName,
 					ResumeTokensCollCapped: true,
 					StreamName:             streamName,
+					Pipeline:               expectedPipeline,
+					ResumeMode:             mongo.ResumeModeStartAfter,
+					MaxTokenAge:            maxTokenAge,
 				})
 			}, 1*time.Second, 100*time.Millisecond)
 		})
@@ -372,6 +510,246 @@ func TestConnector_Run(t *testing.T) {
 		err := conn.Run()
 		require.ErrorIs(t, err, addStreamErr)
 	})
+	t.Run("should stop connector and return error if migration fails", func(t *testing.T) {
+		var (
+			migrateErr  = errors.New("migration error")
+			mongoClient = &mockMongoClient{
+				migrateErr: migrateErr,
+			}
+			natsClient  = &mockNatsClient{}
+			ctx, cancel = context.WithCancel(context.Background())
+			dbName      = "connector-db"
+			collName    = "coll1"
+		)
+		defer cancel()
+
+		conn, _ := New(
+			withMongoClient(mongoClient),
+			withNatsClient(natsClient),
+			WithContext(ctx),
+			WithCollection(dbName, collName),
+		)
+
+		err := conn.Run()
+		require.ErrorIs(t, err, migrateErr)
+	})
+	t.Run("should not run migrations when disabled", func(t *testing.T) {
+		var (
+			mongoClient  = &mockMongoClient{}
+			natsClient   = &mockNatsClient{}
+			ctx, cancel  = context.WithCancel(context.Background())
+			dbName       = "connector-db"
+			collName     = "coll1"
+			tokensDbName = "resume-tokens"
+		)
+		defer cancel()
+
+		conn, _ := New(
+			withMongoClient(mongoClient),
+			withNatsClient(natsClient),
+			WithServerAddr(":0"),
+			WithContext(ctx),
+			WithMigrationsDisabled(),
+			WithCollection(dbName, collName),
+		)
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- conn.Run() }()
+
+		require.Eventually(t, func() bool {
+			return natsClient.StreamWasAdded(nats.AddStreamOptions{StreamName: strings.ToUpper(collName)})
+		}, 1*time.Second, 100*time.Millisecond)
+
+		require.False(t, mongoClient.MigrationsWereRun(mongo.MigrateOptions{
+			DbName:    tokensDbName,
+			CollNames: []string{collName},
+		}))
+
+		cancel()
+		<-errCh
+	})
+	t.Run("should publish to the configured backend", func(t *testing.T) {
+		var (
+			dbName = "connector-db"
+			subj   = "subj"
+			msgId  = "msgId"
+			data   = []byte("event")
+		)
+
+		backends := []struct {
+			name    string
+			collOpt CollectionOption
+		}{
+			{name: "nats default", collOpt: WithTokensDbName("tokens-db")},
+			{name: "custom publisher", collOpt: WithPublisher(&mockPublisherBackend{})},
+		}
+
+		for i, tc := range backends {
+			t.Run(tc.name, func(t *testing.T) {
+				var (
+					mongoClient = &mockMongoClient{}
+					natsClient  = &mockNatsClient{}
+					ctx, cancel = context.WithCancel(context.Background())
+					collName    = "coll" + string(rune('1'+i))
+				)
+				defer cancel()
+
+				conn, err := New(
+					withMongoClient(mongoClient),
+					withNatsClient(natsClient),
+					WithServerAddr(":0"),
+					WithContext(ctx),
+					WithCollection(dbName, collName, tc.collOpt),
+				)
+				require.NoError(t, err)
+
+				errCh := make(chan error, 1)
+				go func() { errCh <- conn.Run() }()
+
+				if mb, ok := conn.options.collections[0].publisher.(*mockPublisherBackend); ok {
+					require.Eventually(t, func() bool {
+						return mb.topicEnsured
+					}, 1*time.Second, 100*time.Millisecond)
+
+					mongoClient.SimulateChangeEvents(subj, msgId, data)
+
+					require.Eventually(t, func() bool {
+						return mb.published(subj, msgId, data)
+					}, 1*time.Second, 100*time.Millisecond)
+				} else {
+					require.Eventually(t, func() bool {
+						return natsClient.StreamWasAdded(nats.AddStreamOptions{StreamName: strings.ToUpper(collName)})
+					}, 1*time.Second, 100*time.Millisecond)
+				}
+
+				cancel()
+				<-errCh
+			})
+		}
+	})
+	t.Run("should resolve a real kafka backend for WithBackend(\"kafka\")", func(t *testing.T) {
+		var (
+			mongoClient = &mockMongoClient{}
+			natsClient  = &mockNatsClient{}
+			ctx, cancel = context.WithCancel(context.Background())
+		)
+		defer cancel()
+
+		conn, err := New(
+			withMongoClient(mongoClient),
+			withNatsClient(natsClient),
+			WithServerAddr(":0"),
+			WithContext(ctx),
+			WithKafkaBrokers([]string{"127.0.0.1:1"}),
+			WithCollection("connector-db", "coll", WithBackend("kafka")),
+		)
+		require.NoError(t, err)
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- conn.Run() }()
+
+		require.Eventually(t, func() bool {
+			return len(conn.backends) == 1
+		}, 1*time.Second, 100*time.Millisecond)
+
+		cancel()
+		require.ErrorIs(t, <-errCh, context.Canceled)
+	})
+	t.Run("should resolve a real mqtt backend for WithBackend(\"mqtt\") and surface its connect error", func(t *testing.T) {
+		var (
+			mongoClient = &mockMongoClient{}
+			natsClient  = &mockNatsClient{}
+			ctx, cancel = context.WithCancel(context.Background())
+		)
+		defer cancel()
+
+		conn, err := New(
+			withMongoClient(mongoClient),
+			withNatsClient(natsClient),
+			WithServerAddr(":0"),
+			WithContext(ctx),
+			WithMqttBrokerUrl("not-a-url"),
+			WithCollection("connector-db", "coll", WithBackend("mqtt")),
+		)
+		require.NoError(t, err)
+
+		err = conn.Run()
+		require.ErrorContains(t, err, "failed to resolve publisher")
+	})
+}
+
+func TestConnector_resolvePublisher(t *testing.T) {
+	t.Run("nats default", func(t *testing.T) {
+		conn := &Connector{options: &options{natsClient: &mockNatsClient{}}}
+
+		backend, err := conn.resolvePublisher(&collection{})
+		require.NoError(t, err)
+		require.NotNil(t, backend)
+	})
+	t.Run("kafka", func(t *testing.T) {
+		conn := &Connector{options: &options{kafkaBrokers: []string{"127.0.0.1:1"}}}
+
+		backend, err := conn.resolvePublisher(&collection{backendName: "kafka"})
+		require.NoError(t, err)
+		require.NotNil(t, backend)
+	})
+	t.Run("unsupported backend name", func(t *testing.T) {
+		conn := &Connector{options: &options{}}
+
+		_, err := conn.resolvePublisher(&collection{backendName: "rabbitmq"})
+		require.ErrorIs(t, err, ErrUnsupportedBackend)
+	})
+	t.Run("explicit publisher bypasses backend name", func(t *testing.T) {
+		conn := &Connector{options: &options{}}
+		mock := &mockPublisherBackend{}
+
+		backend, err := conn.resolvePublisher(&collection{backendName: "kafka", publisher: mock})
+		require.NoError(t, err)
+		require.Same(t, mock, backend)
+	})
+}
+
+// mockPublisherBackend is the publisher.Backend analogue of mockNatsClient,
+// used to exercise backends (e.g. kafka, mqtt) that don't have a real
+// broker available in unit tests.
+type mockPublisherBackend struct {
+	mu           sync.Mutex
+	topicEnsured bool
+	publishOpts  []publisher.PublishOptions
+	closed       bool
+}
+
+func (m *mockPublisherBackend) EnsureTopic(_ context.Context, _ publisher.TopicOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.topicEnsured = true
+	return nil
+}
+
+func (m *mockPublisherBackend) Publish(_ context.Context, opts publisher.PublishOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publishOpts = append(m.publishOpts, opts)
+	return nil
+}
+
+func (m *mockPublisherBackend) published(subj, msgId string, data []byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.ContainsFunc(m.publishOpts, func(po publisher.PublishOptions) bool {
+		return po.Subj == subj && po.MsgId == msgId && bytes.Equal(po.Data, data)
+	})
+}
+
+func (m *mockPublisherBackend) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *mockPublisherBackend) Monitor(_ context.Context) error {
+	return nil
 }
 
 type mockMongoClient struct {
@@ -386,6 +764,10 @@ type mockMongoClient struct {
 	muw                 sync.Mutex
 	watchCollectionOpts []mongo.WatchCollectionOptions
 	watchCollectionErr  error
+
+	mum         sync.Mutex
+	migrateOpts []mongo.MigrateOptions
+	migrateErr  error
 }
 
 func (m *mockMongoClient) Close() error {
@@ -427,6 +809,24 @@ func (m *mockMongoClient) WatchCollection(_ context.Context, opts *mongo.WatchCo
 	return nil
 }
 
+func (m *mockMongoClient) Migrate(_ context.Context, opts *mongo.MigrateOptions) error {
+	if m.migrateErr != nil {
+		return m.migrateErr
+	}
+	m.mum.Lock()
+	defer m.mum.Unlock()
+	m.migrateOpts = append(m.migrateOpts, *opts)
+	return nil
+}
+
+func (m *mockMongoClient) MigrationsWereRun(opts mongo.MigrateOptions) bool {
+	m.mum.Lock()
+	defer m.mum.Unlock()
+	return slices.ContainsFunc(m.migrateOpts, func(o mongo.MigrateOptions) bool {
+		return o.DbName == opts.DbName && slices.Equal(o.CollNames, opts.CollNames)
+	})
+}
+
 func (m *mockMongoClient) CollectionWasWatched(opts mongo.WatchCollectionOptions) bool {
 	m.muw.Lock()
 	defer m.muw.Unlock()
@@ -437,6 +837,10 @@ func (m *mockMongoClient) CollectionWasWatched(opts mongo.WatchCollectionOptions
 			o.ResumeTokensCollName == opts.ResumeTokensCollName &&
 			o.ResumeTokensCollCapped == opts.ResumeTokensCollCapped &&
 			o.StreamName == opts.StreamName &&
+			reflect.DeepEqual(o.Pipeline, opts.Pipeline) &&
+			o.ResumeMode == opts.ResumeMode &&
+			o.StartAtOperationTime == opts.StartAtOperationTime &&
+			o.MaxTokenAge == opts.MaxTokenAge &&
 			o.ChangeEventHandler != nil
 	})
 }
@@ -0,0 +1,29 @@
+package connector
+
+import "errors"
+
+var (
+	// ErrDbNameMissing is returned by WithCollection when dbName is empty.
+	ErrDbNameMissing = errors.New("dbName is required")
+	// ErrCollNameMissing is returned by WithCollection when collName is empty.
+	ErrCollNameMissing = errors.New("collName is required")
+	// ErrInvalidCollSizeInBytes is returned by WithTokensCollCapped when
+	// sizeInBytes is not a positive number.
+	ErrInvalidCollSizeInBytes = errors.New("collSizeInBytes must be greater than 0")
+	// ErrInvalidDbAndCollNames is returned by WithCollection when the resume
+	// tokens would be stored in the same collection that's being watched.
+	ErrInvalidDbAndCollNames = errors.New("tokens db and collection name must differ from the watched db and collection")
+	// ErrUnsupportedBackend is returned by WithBackend when name isn't one
+	// of the backends the connector ships an adapter for.
+	ErrUnsupportedBackend = errors.New("unsupported backend: must be one of \"nats\", \"kafka\", or \"mqtt\"")
+	// ErrUnsupportedPipelineStage is returned by WithChangeStreamPipeline
+	// when a stage isn't one of the stages MongoDB allows in a change
+	// stream aggregation pipeline.
+	ErrUnsupportedPipelineStage = errors.New("unsupported change stream pipeline stage")
+	// ErrInvalidNatsTLSConfig is returned by WithNatsTLS when exactly one
+	// of CertFile/KeyFile is set.
+	ErrInvalidNatsTLSConfig = errors.New("natsTLS: certFile and keyFile must both be set or both be empty")
+	// ErrInvalidMaxTokenAge is returned by WithMaxTokenAge when d is not a
+	// positive duration.
+	ErrInvalidMaxTokenAge = errors.New("maxTokenAge must be greater than 0")
+)
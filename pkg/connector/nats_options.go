@@ -0,0 +1,86 @@
+package connector
+
+import "github.com/context-labs/mongodb-nats-connector/internal/config"
+
+// TLSConfig configures mTLS for the connector's NATS connection.
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// WithNatsTLS configures the NATS connection to use TLS, optionally with a
+// client certificate for mTLS. CertFile and KeyFile must either both be
+// set or both be empty.
+func WithNatsTLS(cfg TLSConfig) Option {
+	return func(o *options) error {
+		if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+			return ErrInvalidNatsTLSConfig
+		}
+
+		o.natsTLS = &cfg
+		return nil
+	}
+}
+
+// WithNatsToken authenticates the NATS connection with a plain token.
+func WithNatsToken(token string) Option {
+	return func(o *options) error {
+		o.natsToken = token
+		return nil
+	}
+}
+
+// WithNatsCredsFile authenticates the NATS connection with a JWT/NKey
+// credentials file (the .creds format produced by `nsc`).
+func WithNatsCredsFile(path string) Option {
+	return func(o *options) error {
+		o.natsCredsFile = path
+		return nil
+	}
+}
+
+// WithNatsNKeySeed authenticates the NATS connection with a raw NKey seed.
+func WithNatsNKeySeed(seed []byte) Option {
+	return func(o *options) error {
+		o.natsNKeySeed = seed
+		return nil
+	}
+}
+
+// WithNatsAuthFromEnv configures NATS TLS/token/creds settings from the
+// environment, reading NATS_CA_FILE, NATS_CERT_FILE, NATS_KEY_FILE,
+// NATS_TOKEN, and NATS_CREDS_FILE. If any of the three TLS variables is
+// set, they together replace any TLS settings an earlier Option configured;
+// otherwise, and for a token or creds file left unset in the environment,
+// whatever an earlier Option configured is kept.
+func WithNatsAuthFromEnv() Option {
+	return func(o *options) error {
+		auth := config.NatsAuthFromEnv()
+
+		if auth.CAFile != "" || auth.CertFile != "" || auth.KeyFile != "" {
+			if err := WithNatsTLS(TLSConfig{
+				CAFile:   auth.CAFile,
+				CertFile: auth.CertFile,
+				KeyFile:  auth.KeyFile,
+			})(o); err != nil {
+				return err
+			}
+		}
+
+		if auth.Token != "" {
+			if err := WithNatsToken(auth.Token)(o); err != nil {
+				return err
+			}
+		}
+
+		if auth.CredsFile != "" {
+			if err := WithNatsCredsFile(auth.CredsFile)(o); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
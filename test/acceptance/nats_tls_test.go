@@ -0,0 +1,33 @@
+//go:build integration
+
+package acceptance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/context-labs/mongodb-nats-connector/test/harness"
+)
+
+// TestConnectorOverTLS boots a TLS-enabled NATS container and asserts the
+// connector, configured with WithNatsTLS, still delivers change events end
+// to end over the encrypted connection.
+func TestConnectorOverTLS(t *testing.T) {
+	ctx := context.Background()
+	h := harness.New(t, harness.FromEnv(), harness.WithTLSNats())
+
+	h.MustStartContainer(ctx, harness.ConnectorTLS)
+	t.Cleanup(func() {
+		h.MustStopContainer(ctx, harness.ConnectorTLS)
+		assert.NoError(t, h.MongoClient.Database("test-connector").Drop(ctx))
+		assert.NoError(t, h.MongoClient.Database("resume-tokens").Drop(ctx))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL1"))
+	})
+
+	h.MustWaitForConnector(10 * time.Second)
+
+	h.MustVerifyMessageCorrectness(50, "test-connector", "coll1", nil)
+}
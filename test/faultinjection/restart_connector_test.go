@@ -21,8 +21,8 @@ func TestRestartConnector(t *testing.T) {
 		h.MustStopContainer(ctx, harness.Connector)
 		assert.NoError(t, h.MongoClient.Database("test-connector").Drop(ctx))
 		assert.NoError(t, h.MongoClient.Database("resume-tokens").Drop(ctx))
-		assert.NoError(t, h.NatsJs.PurgeStream("COLL1"))
-		assert.NoError(t, h.NatsJs.PurgeStream("COLL2"))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL1"))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL2"))
 	})
 
 	h.MustWaitForConnector(10 * time.Second)
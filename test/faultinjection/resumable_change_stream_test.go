@@ -0,0 +1,113 @@
+//go:build integration
+
+package faultinjection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/context-labs/mongodb-nats-connector/test/harness"
+)
+
+// TestResumableChangeStreamError injects failCommand errors on the
+// commands a change stream relies on (getMore/aggregate) and asserts the
+// connector resumes from its stored token without duplicating or dropping
+// events, instead of crashing or stalling.
+func TestResumableChangeStreamError(t *testing.T) {
+	ctx := context.Background()
+	h := harness.New(t, harness.FromEnv())
+
+	h.MustStartContainer(ctx, harness.Connector)
+	t.Cleanup(func() {
+		h.MustStopContainer(ctx, harness.Connector)
+		assert.NoError(t, h.MongoClient.Database("test-connector").Drop(ctx))
+		assert.NoError(t, h.MongoClient.Database("resume-tokens").Drop(ctx))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL1"))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL2"))
+	})
+
+	h.MustWaitForConnector(10 * time.Second)
+
+	testCases := []struct {
+		name string
+		data bson.M
+	}{
+		{
+			name: "interrupted",
+			data: bson.M{
+				"failCommands": bson.A{"getMore", "aggregate"},
+				"errorCode":    11601,
+			},
+		},
+		{
+			name: "host unreachable",
+			data: bson.M{
+				"failCommands": bson.A{"getMore", "aggregate"},
+				"errorCode":    6,
+			},
+		},
+		{
+			name: "resumable change stream error label",
+			data: bson.M{
+				"failCommands":    bson.A{"getMore", "aggregate"},
+				"errorCode":       280,
+				"errorLabels":     bson.A{"ResumableChangeStreamError"},
+				"closeConnection": false,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			beforeSubscribeFunc := func() {
+				h.MustConfigureFailPoint(ctx, harness.FailPoint{
+					ConfigureFailPoint: "failCommand",
+					Mode:               bson.M{"times": 1},
+					Data:               tc.data,
+				})
+			}
+
+			h.MustVerifyMessageCorrectness(100, "test-connector", "coll1", beforeSubscribeFunc)
+		})
+	}
+}
+
+// TestResumeTokenPersistenceFailure injects a failCommand error on inserts
+// into the resume-tokens collection to verify the connector's behavior
+// when token persistence transiently fails: events already delivered to
+// NATS must not be dropped or duplicated once persistence recovers.
+func TestResumeTokenPersistenceFailure(t *testing.T) {
+	ctx := context.Background()
+	h := harness.New(t, harness.FromEnv())
+
+	h.MustStartContainer(ctx, harness.Connector)
+	t.Cleanup(func() {
+		h.MustStopContainer(ctx, harness.Connector)
+		assert.NoError(t, h.MongoClient.Database("test-connector").Drop(ctx))
+		assert.NoError(t, h.MongoClient.Database("resume-tokens").Drop(ctx))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL1"))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL2"))
+	})
+
+	h.MustWaitForConnector(10 * time.Second)
+
+	beforeSubscribeFunc := func() {
+		h.MustConfigureFailPoint(ctx, harness.FailPoint{
+			ConfigureFailPoint: "failCommand",
+			Mode:               bson.M{"times": 1},
+			Data: bson.M{
+				"failCommands":         bson.A{"insert"},
+				"errorCode":            11600,
+				"namespace":            "resume-tokens.coll1",
+				"closeConnection":      false,
+				"failInternalCommands": true,
+			},
+		})
+	}
+
+	h.MustVerifyMessageCorrectness(100, "test-connector", "coll1", beforeSubscribeFunc)
+}
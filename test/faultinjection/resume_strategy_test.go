@@ -0,0 +1,76 @@
+//go:build integration
+
+package faultinjection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/context-labs/mongodb-nats-connector/test/harness"
+)
+
+// TestResumeFallsBackWhenStoredTokenIsGone deletes the persisted resume
+// tokens while the connector runs, simulating the stored position being
+// lost, and asserts every subsequent insert is still delivered exactly once
+// instead of the connector crashing or stalling. A change stream reopened
+// with no stored token starts at the current time.
+func TestResumeFallsBackWhenStoredTokenIsGone(t *testing.T) {
+	ctx := context.Background()
+	h := harness.New(t, harness.FromEnv())
+
+	h.MustStartContainer(ctx, harness.Connector)
+	t.Cleanup(func() {
+		h.MustStopContainer(ctx, harness.Connector)
+		assert.NoError(t, h.MongoClient.Database("test-connector").Drop(ctx))
+		assert.NoError(t, h.MongoClient.Database("resume-tokens").Drop(ctx))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL1"))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL2"))
+	})
+
+	h.MustWaitForConnector(10 * time.Second)
+
+	beforeSubscribeFunc := func() {
+		_, err := h.MongoClient.Database("resume-tokens").Collection("coll1").DeleteMany(ctx, bson.M{})
+		assert.NoError(t, err)
+	}
+
+	h.MustVerifyMessageCorrectness(100, "test-connector", "coll1", beforeSubscribeFunc)
+}
+
+// TestResumeFallsBackOnDroppedOplogWindow injects the
+// "ChangeStreamHistoryLost" error MongoDB returns when the oplog window has
+// rolled past a change stream's position, and asserts the connector
+// recovers by reopening its change stream (resolving resume options from
+// the stored token again) rather than stalling.
+func TestResumeFallsBackOnDroppedOplogWindow(t *testing.T) {
+	ctx := context.Background()
+	h := harness.New(t, harness.FromEnv())
+
+	h.MustStartContainer(ctx, harness.Connector)
+	t.Cleanup(func() {
+		h.MustStopContainer(ctx, harness.Connector)
+		assert.NoError(t, h.MongoClient.Database("test-connector").Drop(ctx))
+		assert.NoError(t, h.MongoClient.Database("resume-tokens").Drop(ctx))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL1"))
+		assert.NoError(t, h.PurgeStream(ctx, "COLL2"))
+	})
+
+	h.MustWaitForConnector(10 * time.Second)
+
+	beforeSubscribeFunc := func() {
+		h.MustConfigureFailPoint(ctx, harness.FailPoint{
+			ConfigureFailPoint: "failCommand",
+			Mode:               bson.M{"times": 1},
+			Data: bson.M{
+				"failCommands": bson.A{"getMore", "aggregate"},
+				"errorCode":    286, // ChangeStreamHistoryLost
+			},
+		})
+	}
+
+	h.MustVerifyMessageCorrectness(100, "test-connector", "coll1", beforeSubscribeFunc)
+}
@@ -0,0 +1,40 @@
+package harness
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FailPoint describes a MongoDB fail point to configure via
+// db.adminCommand({configureFailPoint: ..., mode: ..., data: ...}).
+// ConfigureFailPoint is the fail point's name, e.g. "failCommand".
+type FailPoint struct {
+	ConfigureFailPoint string
+	Mode               any
+	Data               bson.M
+}
+
+// MustConfigureFailPoint configures fp against the harness's replica set
+// and registers a t.Cleanup that disables it, so a test can inject faults
+// without leaking them into later tests.
+func (h *Harness) MustConfigureFailPoint(ctx context.Context, fp FailPoint) {
+	h.t.Helper()
+
+	admin := h.MongoClient.Database("admin")
+
+	err := admin.RunCommand(ctx, bson.D{
+		{Key: "configureFailPoint", Value: fp.ConfigureFailPoint},
+		{Key: "mode", Value: fp.Mode},
+		{Key: "data", Value: fp.Data},
+	}).Err()
+	require.NoError(h.t, err)
+
+	h.t.Cleanup(func() {
+		_ = admin.RunCommand(context.Background(), bson.D{
+			{Key: "configureFailPoint", Value: fp.ConfigureFailPoint},
+			{Key: "mode", Value: "off"},
+		}).Err()
+	})
+}
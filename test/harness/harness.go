@@ -0,0 +1,301 @@
+// Package harness drives the docker-compose stack used by the integration
+// and fault-injection test suites: starting/stopping the connector
+// container, and asserting on the MongoDB and NATS state it produces.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// composeFile is the docker-compose file the harness drives, relative to
+// the package under test's working directory. Overridable via COMPOSE_FILE
+// for out-of-tree layouts.
+var composeFile = envOr("COMPOSE_FILE", "docker-compose.yml")
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// Container identifies one of the services in the docker-compose stack the
+// harness controls.
+type Container string
+
+// Connector is the connector service under test.
+const Connector Container = "connector"
+
+// ConnectorTLS is the connector service configured against a TLS-enabled
+// NATS listener, used to exercise WithNatsTLS end to end.
+const ConnectorTLS Container = "connector-tls"
+
+// Option configures a Harness.
+type Option func(*Harness)
+
+// FromEnv reads the MongoDB, NATS, and connector health check addresses the
+// harness dials from MONGO_URI, NATS_URL, and CONNECTOR_ADDR, falling back
+// to the stack's default docker-compose addresses.
+func FromEnv() Option {
+	return func(h *Harness) {
+		if uri := os.Getenv("MONGO_URI"); uri != "" {
+			h.mongoUri = uri
+		}
+		if url := os.Getenv("NATS_URL"); url != "" {
+			h.natsUrl = url
+		}
+		if addr := os.Getenv("CONNECTOR_ADDR"); addr != "" {
+			h.connectorAddr = addr
+		}
+	}
+}
+
+// WithTLSNats points the harness's NATS URL at the TLS-enabled listener
+// exposed by the docker-compose stack's "nats-tls" service.
+func WithTLSNats() Option {
+	return func(h *Harness) {
+		h.natsUrl = "tls://localhost:4443"
+	}
+}
+
+// Harness wires up the clients and container controls the integration
+// suites share.
+type Harness struct {
+	t   *testing.T
+	ctx context.Context
+
+	mongoUri      string
+	natsUrl       string
+	connectorAddr string
+
+	MongoClient *mongo.Client
+	NatsJs      jetstream.JetStream
+}
+
+// New connects to MongoDB and NATS and returns a Harness ready to drive the
+// connector under test. It fails the test immediately if either connection
+// can't be established.
+func New(t *testing.T, opts ...Option) *Harness {
+	t.Helper()
+
+	h := &Harness{
+		t:             t,
+		ctx:           context.Background(),
+		mongoUri:      "mongodb://localhost:27017/?replicaSet=rs0",
+		natsUrl:       "nats://localhost:4222",
+		connectorAddr: "http://localhost:8080",
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	mongoClient, err := mongo.Connect(h.ctx, mongooptions.Client().ApplyURI(h.mongoUri))
+	require.NoError(t, err)
+	h.MongoClient = mongoClient
+
+	natsConn, err := nats.Connect(h.natsUrl)
+	require.NoError(t, err)
+	t.Cleanup(natsConn.Close)
+
+	natsJs, err := jetstream.New(natsConn)
+	require.NoError(t, err)
+	h.NatsJs = natsJs
+
+	return h
+}
+
+// MustStartContainer starts the given service and fails the test if it
+// doesn't come up.
+func (h *Harness) MustStartContainer(ctx context.Context, c Container) {
+	h.t.Helper()
+	require.NoError(h.t, runCompose(ctx, "up", "-d", string(c)))
+}
+
+// MustStopContainer stops the given service and fails the test if it
+// doesn't go down cleanly.
+func (h *Harness) MustStopContainer(ctx context.Context, c Container) {
+	h.t.Helper()
+	require.NoError(h.t, runCompose(ctx, "stop", string(c)))
+}
+
+// MustWaitForConnector blocks until the connector's health check server
+// responds, up to timeout.
+func (h *Harness) MustWaitForConnector(timeout time.Duration) {
+	h.t.Helper()
+	require.Eventually(h.t, func() bool {
+		return h.connectorIsUp()
+	}, timeout, 250*time.Millisecond, "connector never came up at %s", h.connectorAddr)
+}
+
+// MustEnsureConnectorIsUpFor asserts the connector stays up for the given
+// duration, rather than crashing shortly after some triggering action.
+func (h *Harness) MustEnsureConnectorIsUpFor(d time.Duration) {
+	h.t.Helper()
+
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		require.True(h.t, h.connectorIsUp(), "connector went down at %s before %s elapsed", h.connectorAddr, d)
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// connectorIsUp reports whether the connector's health check server is
+// accepting connections. Any HTTP response, including a 404 from the
+// absence of a registered handler, counts as up: the point is to observe
+// the process serving, not to validate a particular route.
+func (h *Harness) connectorIsUp() bool {
+	resp, err := http.Get(h.connectorAddr)
+	if err != nil {
+		return false
+	}
+	_ = resp.Body.Close()
+	return true
+}
+
+// PurgeStream removes all messages from the named JetStream stream,
+// without tearing it down, so tests can reset state between cases.
+func (h *Harness) PurgeStream(ctx context.Context, streamName string) error {
+	stream, err := h.NatsJs.Stream(ctx, streamName)
+	if err != nil {
+		return err
+	}
+	return stream.Purge(ctx)
+}
+
+// MustMongoRenameCollection renames a collection in dbName from fromColl to
+// toColl.
+func (h *Harness) MustMongoRenameCollection(ctx context.Context, dbName, fromColl, toColl string) {
+	h.t.Helper()
+	admin := h.MongoClient.Database("admin")
+	err := admin.RunCommand(ctx, bson.D{
+		{Key: "renameCollection", Value: fmt.Sprintf("%s.%s", dbName, fromColl)},
+		{Key: "to", Value: fmt.Sprintf("%s.%s", dbName, toColl)},
+	}).Err()
+	require.NoError(h.t, err)
+}
+
+// MustNotReceiveNatsMsg asserts no message arrives on subj within timeout.
+func (h *Harness) MustNotReceiveNatsMsg(subj string, timeout time.Duration) {
+	h.t.Helper()
+
+	streamName, _, _ := strings.Cut(subj, ".")
+
+	consumer, err := h.NatsJs.OrderedConsumer(h.ctx, streamName, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{subj},
+	})
+	require.NoError(h.t, err)
+
+	msgs, err := consumer.Messages()
+	require.NoError(h.t, err)
+
+	type result struct {
+		msg jetstream.Msg
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		msg, err := msgs.Next()
+		resCh <- result{msg, err}
+	}()
+
+	select {
+	case <-time.After(timeout):
+		msgs.Stop()
+		return
+	case res := <-resCh:
+		msgs.Stop()
+		require.NoError(h.t, res.err)
+		_ = res.msg.Ack()
+		require.Failf(h.t, "unexpected message", "received a message on %s within %s", subj, timeout)
+	}
+}
+
+// MustVerifyMessageCorrectness inserts n documents into dbName.collName,
+// invoking beforeSubscribe right before, and asserts every insert is
+// delivered to NATS exactly once, with no duplicates or gaps: the set of
+// documentKey._id values carried by the delivered "insert" messages must
+// equal the set of _ids MongoDB assigned the inserted documents.
+func (h *Harness) MustVerifyMessageCorrectness(n int, dbName, collName string, beforeSubscribe func()) {
+	h.t.Helper()
+
+	streamName := strings.ToUpper(collName)
+
+	consumer, err := h.NatsJs.OrderedConsumer(h.ctx, streamName, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{streamName + ".insert"},
+	})
+	require.NoError(h.t, err)
+
+	msgs, err := consumer.Messages()
+	require.NoError(h.t, err)
+	defer msgs.Stop()
+
+	if beforeSubscribe != nil {
+		beforeSubscribe()
+	}
+
+	coll := h.MongoClient.Database(dbName).Collection(collName)
+
+	wantIds := make(map[string]struct{}, n)
+	for i := 0; i < n; i++ {
+		res, err := coll.InsertOne(h.ctx, bson.M{"seq": i})
+		require.NoError(h.t, err)
+		wantIds[documentKeyID(res.InsertedID)] = struct{}{}
+	}
+
+	gotIds := make(map[string]struct{}, n)
+	for len(gotIds) < n {
+		msg, err := msgs.Next()
+		require.NoError(h.t, err)
+
+		var event bson.M
+		require.NoError(h.t, bson.UnmarshalExtJSON(msg.Data(), false, &event))
+		require.NoError(h.t, msg.Ack())
+
+		documentKey, _ := event["documentKey"].(bson.M)
+		id := documentKeyID(documentKey["_id"])
+
+		_, dup := gotIds[id]
+		require.Falsef(h.t, dup, "received a duplicate message for document %s", id)
+		gotIds[id] = struct{}{}
+	}
+
+	require.Equal(h.t, wantIds, gotIds, "delivered message ids must match inserted document ids exactly")
+}
+
+// documentKeyID renders a MongoDB document id as extended JSON, giving a
+// comparable string key regardless of the id's underlying BSON type.
+func documentKeyID(id any) string {
+	b, err := bson.MarshalExtJSON(bson.M{"_id": id}, false, false)
+	if err != nil {
+		return fmt.Sprintf("%v", id)
+	}
+	return string(b)
+}
+
+// runCompose shells out to `docker compose -f composeFile <args>`, used by
+// MustStartContainer/MustStopContainer to drive the stack under test.
+func runCompose(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"compose", "-f", composeFile}, args...)...)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker compose %s: %w: %s", strings.Join(args, " "), err, out)
+	}
+
+	return nil
+}